@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// CNIProvider installs a CNI plugin into a workload cluster and reports
+// when its components are ready.
+type CNIProvider interface {
+	Apply(kubeconfig string) error
+	WaitReady(kubeconfig string) error
+}
+
+// manifestCNI applies a single static manifest URL/path and waits for
+// every deployment in all namespaces to become available. This is
+// sufficient for all of the built-in CNI choices below.
+type manifestCNI struct {
+	manifest string
+}
+
+func (c *manifestCNI) Apply(kubeconfig string) error {
+	args := []string{"--kubeconfig", kubeconfig, "apply", "-f", c.manifest}
+	return process.ExecJUnit("kubectl", args, os.Environ())
+}
+
+func (c *manifestCNI) WaitReady(kubeconfig string) error {
+	args := []string{"--kubeconfig", kubeconfig, "wait", "--for=condition=Available", "--all", "--all-namespaces", "deployment", "--timeout=-1m"}
+	return process.ExecJUnit("kubectl", args, os.Environ())
+}
+
+// known-good default versions for the built-in CNI choices. These are
+// templated into manifestURLTemplates below.
+var defaultCNIVersions = map[string]string{
+	"calico":  "v3.12",
+	"cilium":  "v1.11.0",
+	"flannel": "v0.17.0",
+	"antrea":  "v1.4.0",
+	"weave":   "v2.8.1",
+}
+
+// manifestURLTemplates gives the canonical install manifest for each
+// built-in CNI, with %s substituted for the resolved version.
+var manifestURLTemplates = map[string]string{
+	"calico":  "https://docs.projectcalico.org/%s/manifests/calico.yaml",
+	"cilium":  "https://raw.githubusercontent.com/cilium/cilium/%s/install/kubernetes/quick-install.yaml",
+	"flannel": "https://github.com/flannel-io/flannel/releases/download/%s/kube-flannel.yml",
+	"antrea":  "https://github.com/antrea-io/antrea/releases/download/%s/antrea.yml",
+	"weave":   "https://github.com/weaveworks/weave/releases/download/%s/weave-daemonset-k8s.yaml",
+}
+
+// noneCNI is a no-op CNIProvider used when the user does not want
+// kubetest2 to install a CNI itself (e.g. the provider's infra template
+// already bundles one).
+type noneCNI struct{}
+
+func (noneCNI) Apply(kubeconfig string) error     { return nil }
+func (noneCNI) WaitReady(kubeconfig string) error { return nil }
+
+// newCNIProvider resolves the --cni flag (name or name,version) and the
+// --cni-manifest escape hatch into a CNIProvider.
+func newCNIProvider(cni, manifestOverride string) (CNIProvider, error) {
+	if manifestOverride != "" {
+		return &manifestCNI{manifest: manifestOverride}, nil
+	}
+	if cni == "" || cni == "none" {
+		return noneCNI{}, nil
+	}
+
+	name, version := cni, ""
+	if idx := strings.Index(cni, ","); idx != -1 {
+		name, version = cni[:idx], cni[idx+1:]
+	}
+
+	tmpl, ok := manifestURLTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown --cni %q: must be one of calico, cilium, flannel, antrea, weave, none", name)
+	}
+	if version == "" {
+		version = defaultCNIVersions[name]
+	}
+	return &manifestCNI{manifest: fmt.Sprintf(tmpl, version)}, nil
+}
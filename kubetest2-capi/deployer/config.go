@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderVersion pins a single CAPI provider (core, bootstrap,
+// control-plane, or infrastructure) to a specific release, optionally
+// overriding the container image(s) it ships with.
+type ProviderVersion struct {
+	Name           string            `yaml:"name"`
+	Version        string            `yaml:"version"`
+	ImageOverrides map[string]string `yaml:"imageOverrides,omitempty"`
+}
+
+// E2EConfig is modeled on the CAPI test framework's E2EConfig: it
+// declares the provider versions to install, the cluster-template to
+// render, and the variables to substitute into it.
+type E2EConfig struct {
+	Core                ProviderVersion   `yaml:"core"`
+	Bootstrap           ProviderVersion   `yaml:"bootstrap"`
+	ControlPlane        ProviderVersion   `yaml:"controlPlane"`
+	Infrastructure      ProviderVersion   `yaml:"infrastructure"`
+	ClusterTemplatePath string            `yaml:"clusterTemplatePath"`
+	Variables           map[string]string `yaml:"variables,omitempty"`
+}
+
+// loadE2EConfig reads and parses the --config file at path.
+func loadE2EConfig(path string) (*E2EConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read e2e config %q: %w", path, err)
+	}
+	config := &E2EConfig{}
+	if err := yaml.Unmarshal(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse e2e config %q: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *E2EConfig) validate() error {
+	if c.Infrastructure.Name == "" {
+		return fmt.Errorf("e2e config: infrastructure.name is required")
+	}
+	return nil
+}
+
+// variablesToEnv turns the config's variable substitutions into
+// KEY=VALUE entries suitable for appending to a command's environment,
+// which is how clusterctl resolves ${VARIABLE} references in templates.
+func variablesToEnv(variables map[string]string) []string {
+	env := make([]string, 0, len(variables))
+	for k, v := range variables {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// initArgs builds the "clusterctl init" flags for the pinned providers,
+// e.g. --core cluster-api:v1.0.0 --infrastructure aws:v1.2.3, plus a
+// --config pointing at a generated clusterctl config file when any
+// provider declares imageOverrides.
+func (c *E2EConfig) initArgs() ([]string, error) {
+	var args []string
+	for flag, p := range map[string]ProviderVersion{
+		"--core":           c.Core,
+		"--bootstrap":      c.Bootstrap,
+		"--control-plane":  c.ControlPlane,
+		"--infrastructure": c.Infrastructure,
+	} {
+		if p.Name == "" {
+			continue
+		}
+		ref := p.Name
+		if p.Version != "" {
+			ref += ":" + p.Version
+		}
+		args = append(args, flag, ref)
+	}
+
+	clusterctlConfig, err := c.writeClusterctlConfig()
+	if err != nil {
+		return nil, err
+	}
+	if clusterctlConfig != "" {
+		args = append(args, "--config", clusterctlConfig)
+	}
+	return args, nil
+}
+
+// writeClusterctlConfig renders any provider imageOverrides into a
+// clusterctl config file's "images" section (the mechanism clusterctl
+// itself uses to override the repository/tag a provider's components
+// are installed from: https://cluster-api.sigs.k8s.io/clusterctl/configuration#image-overrides)
+// and returns its path, or "" if no provider declares overrides.
+func (c *E2EConfig) writeClusterctlConfig() (string, error) {
+	images := map[string]map[string]string{}
+	for _, p := range []ProviderVersion{c.Core, c.Bootstrap, c.ControlPlane, c.Infrastructure} {
+		if p.Name == "" || len(p.ImageOverrides) == 0 {
+			continue
+		}
+		images[p.Name] = p.ImageOverrides
+	}
+	if len(images) == 0 {
+		return "", nil
+	}
+
+	out, err := yaml.Marshal(map[string]interface{}{"images": images})
+	if err != nil {
+		return "", err
+	}
+	tmpfile, err := ioutil.TempFile("", "kubetest2-capi-clusterctl-config-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer tmpfile.Close()
+	if _, err := tmpfile.Write(out); err != nil {
+		return "", err
+	}
+	return tmpfile.Name(), nil
+}
@@ -42,10 +42,16 @@ func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
 	kind, flags := kinddeployer.New(opts)
 	d := &deployer{
 		kind:          kind.(*kinddeployer.Deployer),
+		k3d:           &k3dManagementCluster{},
+		minikube:      &minikubeManagementCluster{},
+		external:      &externalManagementCluster{},
 		commonOptions: opts,
 	}
 	// register flags and return
 	bindFlags(d, flags)
+	bindK3DFlags(d.k3d, flags)
+	bindMinikubeFlags(d.minikube, flags)
+	bindExternalFlags(d.external, flags)
 	return d, flags
 }
 
@@ -55,18 +61,30 @@ var _ types.NewDeployer = New
 type deployer struct {
 	// generic parts
 	commonOptions types.Options
-	kind          *kinddeployer.Deployer
+	// management cluster backends; the one selected via --management-cluster
+	// (or --use-existing-cluster) is resolved at call time
+	kind     *kinddeployer.Deployer
+	k3d      *k3dManagementCluster
+	minikube *minikubeManagementCluster
+	external *externalManagementCluster
 	// capi specific details
-	provider            string
-	kubernetesVersion   string
-	controlPlaneCount   string
-	workerCount         string
-	flavor              string
-	useExistingCluster  bool
-	installCalico       bool
-	kubecfgPath         string
-	upTimeout           string
-	workloadClusterName string
+	provider                 string
+	kubernetesVersion        string
+	controlPlaneCount        string
+	workerCount              string
+	flavor                   string
+	managementCluster        string
+	useExistingCluster       bool
+	cni                      string
+	cniManifest              string
+	kubecfgPath              string
+	upTimeout                string
+	workloadClusterName      string
+	configPath               string
+	e2eConfig                *E2EConfig
+	upgradeTo                string
+	upgradeImage             string
+	infraMachineTemplateKind string
 }
 
 func (d *deployer) Kubeconfig() (string, error) {
@@ -82,7 +100,16 @@ func (d *deployer) Kubeconfig() (string, error) {
 	args := []string{
 		"get", "kubeconfig", d.workloadClusterName,
 	}
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return "", err
+	}
+	mgmtEnv, err := managementClusterEnv(mgmt)
+	if err != nil {
+		return "", err
+	}
 	clusterctl := exec.Command("clusterctl", args...)
+	clusterctl.Env = mgmtEnv
 	lines, err := clusterctl.Output()
 	if err != nil {
 		return "", err
@@ -111,18 +138,36 @@ func bindFlags(d *deployer, flags *pflag.FlagSet) {
 	flags.StringVar(
 		&d.flavor, "flavor", "", "--flavor flag for clusterctl",
 	)
+	flags.StringVar(
+		&d.managementCluster, "management-cluster", "kind", "the management cluster backend to install Cluster API into: kind, k3d, minikube, or external",
+	)
 	flags.BoolVar(
-		&d.useExistingCluster, "use-existing-cluster", false, "use the existing, currently targeted cluster as the management cluster",
+		&d.useExistingCluster, "use-existing-cluster", false, "sugar for --management-cluster=external: use the existing, currently targeted cluster as the management cluster",
 	)
 	flags.StringVar(
 		&d.upTimeout, "up-timeout", "30m", "maximum time allotted for the --up command to complete",
 	)
-	flags.BoolVar(
-		&d.installCalico, "install-calico", false, "automatically install the Calico CNI when the cluster becomes available",
+	flags.StringVar(
+		&d.cni, "cni", "", "CNI to install once the cluster becomes available: calico, cilium, flannel, antrea, weave, or none (optionally suffixed with ,<version>)",
+	)
+	flags.StringVar(
+		&d.cniManifest, "cni-manifest", "", "path or URL to a custom CNI manifest to apply instead of a built-in --cni choice",
 	)
 	flags.StringVar(
 		&d.workloadClusterName, "workload-cluster-name", "capi-workload-cluster", "the workload cluster name",
 	)
+	flags.StringVar(
+		&d.configPath, "config", "", "path to an E2EConfig YAML file declaring provider versions, image overrides, and cluster-template variables",
+	)
+	flags.StringVar(
+		&d.upgradeTo, "upgrade-to", "", "if set, upgrade the workload cluster's control-plane and worker machines to this Kubernetes version after Up() succeeds",
+	)
+	flags.StringVar(
+		&d.upgradeImage, "upgrade-image", "", "if set alongside --upgrade-to, also patch the infrastructure machine templates to this image reference",
+	)
+	flags.StringVar(
+		&d.infraMachineTemplateKind, "infrastructure-machine-template-kind", "", "the provider-specific machine-template resource kind to patch for --upgrade-image, e.g. dockermachinetemplates; defaults to \"<provider>machinetemplates\"",
+	)
 }
 
 // assert that deployer implements types.DeployerWithKubeconfig
@@ -138,15 +183,35 @@ func (d *deployer) Up() error {
 	ctx, cancel := context.WithTimeout(context.Background(), upTimeout)
 	defer cancel()
 
-	if !d.useExistingCluster {
-		if err := d.kind.Up(); err != nil {
+	if d.configPath != "" {
+		e2eConfig, err := loadE2EConfig(d.configPath)
+		if err != nil {
 			return err
 		}
+		d.e2eConfig = e2eConfig
+	}
+
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return err
+	}
+	if err := mgmt.Up(); err != nil {
+		return err
+	}
+	mgmtEnv, err := managementClusterEnv(mgmt)
+	if err != nil {
+		return err
+	}
+
+	provider := d.provider
+	if d.e2eConfig != nil {
+		provider = d.e2eConfig.Infrastructure.Name
 	}
 
 	println("Up(): installing Cluster API...\n")
-	args := []string{"get", "providers", "--all-namespaces", fmt.Sprintf("--field-selector=metadata.name=infrastructure-%s", d.provider), "--ignore-not-found"}
+	args := []string{"get", "providers", "--all-namespaces", fmt.Sprintf("--field-selector=metadata.name=infrastructure-%s", provider), "--ignore-not-found"}
 	kubectl := exec.CommandContext(ctx, "kubectl", args...)
+	kubectl.Env = mgmtEnv
 	lines, err := kubectl.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -158,36 +223,65 @@ func (d *deployer) Up() error {
 		}
 	}
 	if len(lines) == 0 { // no results
-		args = []string{"init", "--infrastructure", d.provider}
-		if err := process.ExecJUnitContext(ctx, "clusterctl", args, os.Environ()); err != nil {
+		if d.e2eConfig != nil {
+			initArgs, err := d.e2eConfig.initArgs()
+			if err != nil {
+				return err
+			}
+			args = append([]string{"init"}, initArgs...)
+		} else {
+			args = []string{"init", "--infrastructure", provider}
+		}
+		if err := process.ExecJUnitContext(ctx, "clusterctl", args, mgmtEnv); err != nil {
 			return err
 		}
 	}
 
 	println("waiting for CAPI to start")
 	args = []string{"wait", "--for=condition=Available", "--all", "--all-namespaces", "deployment", "--timeout=-1m"}
-	if err := process.ExecJUnitContext(ctx, "kubectl", args, os.Environ()); err != nil {
+	if err := process.ExecJUnitContext(ctx, "kubectl", args, mgmtEnv); err != nil {
 		return err
 	}
 
-	args = []string{
-		"config",
-		"cluster", d.workloadClusterName,
-		"--infrastructure", d.provider,
-		"--kubernetes-version", d.kubernetesVersion,
-		"--worker-machine-count", d.workerCount,
-		"--control-plane-machine-count", d.controlPlaneCount,
-		"--flavor", d.flavor,
+	if d.e2eConfig != nil {
+		args = []string{
+			"generate",
+			"cluster", d.workloadClusterName,
+			"--infrastructure", provider,
+			"--kubernetes-version", d.kubernetesVersion,
+			"--worker-machine-count", d.workerCount,
+			"--control-plane-machine-count", d.controlPlaneCount,
+		}
+		if d.e2eConfig.ClusterTemplatePath != "" {
+			args = append(args, "--from", d.e2eConfig.ClusterTemplatePath)
+		} else if d.flavor != "" {
+			args = append(args, "--flavor", d.flavor)
+		}
+	} else {
+		args = []string{
+			"config",
+			"cluster", d.workloadClusterName,
+			"--infrastructure", provider,
+			"--kubernetes-version", d.kubernetesVersion,
+			"--worker-machine-count", d.workerCount,
+			"--control-plane-machine-count", d.controlPlaneCount,
+			"--flavor", d.flavor,
+		}
 	}
 
 	clusterctl := exec.CommandContext(ctx, "clusterctl", args...)
 	clusterctl.Stderr = os.Stderr
+	clusterctl.Env = mgmtEnv
+	if d.e2eConfig != nil {
+		clusterctl.Env = append(mgmtEnv, variablesToEnv(d.e2eConfig.Variables)...)
+	}
 	stdout, err := clusterctl.StdoutPipe()
 	if err != nil {
 		return err
 	}
 
 	kubectl = exec.CommandContext(ctx, "kubectl", "apply", "-f", "-")
+	kubectl.Env = mgmtEnv
 	kubectl.Stdin = stdout
 	kubectl.Stdout = os.Stdout
 	kubectl.Stderr = os.Stderr
@@ -207,20 +301,29 @@ func (d *deployer) Up() error {
 
 	println("waiting for cluster to become ready")
 	args = []string{"wait", "--for=condition=Ready", "cluster/" + d.workloadClusterName, "--timeout=-1m"}
-	if err := process.ExecJUnitContext(ctx, "kubectl", args, os.Environ()); err != nil {
+	if err := process.ExecJUnitContext(ctx, "kubectl", args, mgmtEnv); err != nil {
 		return err
 	}
-	if d.installCalico {
+	if d.cni != "" && d.cni != "none" || d.cniManifest != "" {
 		kubeconfig, err := d.Kubeconfig()
 		if err != nil {
 			return err
 		}
-		args = []string{"--kubeconfig", kubeconfig, "apply", "-f", "https://docs.projectcalico.org/v3.12/manifests/calico.yaml"}
-		if err := process.ExecJUnitContext(ctx, "kubectl", args, os.Environ()); err != nil {
+		cni, err := newCNIProvider(d.cni, d.cniManifest)
+		if err != nil {
+			return err
+		}
+		println("Up(): installing CNI...\n")
+		if err := cni.Apply(kubeconfig); err != nil {
+			return err
+		}
+		if err := cni.WaitReady(kubeconfig); err != nil {
 			return err
 		}
-		args = []string{"--kubeconfig", kubeconfig, "wait", "--for=condition=Available", "--all", "--all-namespaces", "deployment", "--timeout=-1m"}
-		if err := process.ExecJUnitContext(ctx, "kubectl", args, os.Environ()); err != nil {
+	}
+
+	if d.upgradeTo != "" {
+		if err := d.Upgrade(d.upgradeTo, mgmtEnv); err != nil {
 			return err
 		}
 	}
@@ -229,22 +332,46 @@ func (d *deployer) Up() error {
 }
 
 func (d *deployer) Down() error {
-	args := []string{"delete", "--ignore-not-found", "--wait", "cluster", d.kind.ClusterName}
-	if err := process.ExecJUnit("kubectl", args, os.Environ()); err != nil {
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return err
+	}
+	mgmtEnv, err := managementClusterEnv(mgmt)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"delete", "--ignore-not-found", "--wait", "cluster", d.workloadClusterName}
+	if err := process.ExecJUnit("kubectl", args, mgmtEnv); err != nil {
 		return err
 	}
 
-	return d.kind.Down()
+	return mgmt.Down()
 }
 
 func (d *deployer) IsUp() (up bool, err error) {
-	return d.kind.IsUp()
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return false, err
+	}
+	return mgmt.IsUp()
 }
 
 func (d *deployer) DumpClusterLogs() error {
-	return d.kind.DumpClusterLogs()
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return err
+	}
+	return mgmt.DumpClusterLogs()
 }
 
 func (d *deployer) Build() error {
-	return d.kind.Build()
-}
\ No newline at end of file
+	mgmt, err := d.resolveManagementCluster()
+	if err != nil {
+		return err
+	}
+	if b, ok := mgmt.(managementClusterBuilder); ok {
+		return b.Build()
+	}
+	return fmt.Errorf("--management-cluster=%s does not support building a node image", d.managementCluster)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+	kinddeployer "sigs.k8s.io/kubetest2/kubetest2-kind/deployer"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// ManagementCluster abstracts the bootstrap/management cluster that
+// Cluster API is installed into, so kubetest2-capi is not hard-coded to
+// kind.
+type ManagementCluster interface {
+	Up() error
+	Down() error
+	Kubeconfig() (string, error)
+	IsUp() (bool, error)
+	DumpClusterLogs() error
+}
+
+// managementClusterBuilder is implemented by backends (currently only
+// kind) that can build their own node image via `kubetest2 ... --build`.
+type managementClusterBuilder interface {
+	Build() error
+}
+
+// assert that the existing kind deployer satisfies ManagementCluster
+// without any wrapping.
+var _ ManagementCluster = (*kinddeployer.Deployer)(nil)
+
+// resolveManagementCluster returns the ManagementCluster backend named
+// by --management-cluster, honoring --use-existing-cluster as sugar for
+// "external".
+func (d *deployer) resolveManagementCluster() (ManagementCluster, error) {
+	name := d.managementCluster
+	if d.useExistingCluster {
+		name = "external"
+	}
+	switch name {
+	case "", "kind":
+		return d.kind, nil
+	case "k3d":
+		return d.k3d, nil
+	case "minikube":
+		return d.minikube, nil
+	case "external":
+		return d.external, nil
+	default:
+		return nil, fmt.Errorf("unknown --management-cluster %q: must be one of kind, k3d, minikube, external", name)
+	}
+}
+
+// managementClusterEnv returns os.Environ() with KUBECONFIG pointed at
+// mgmt's kubeconfig, so the kubectl/clusterctl invocations in deployer.go
+// talk to the selected management cluster rather than whatever context
+// happens to be current.
+func managementClusterEnv(mgmt ManagementCluster) ([]string, error) {
+	kubeconfig, err := mgmt.Kubeconfig()
+	if err != nil {
+		return nil, err
+	}
+	return append(os.Environ(), "KUBECONFIG="+kubeconfig), nil
+}
+
+// k3dManagementCluster runs Cluster API against a k3d-managed bootstrap
+// cluster.
+type k3dManagementCluster struct {
+	clusterName string
+}
+
+func bindK3DFlags(d *k3dManagementCluster, flags *pflag.FlagSet) {
+	flags.StringVar(
+		&d.clusterName, "k3d-cluster-name", "capi-k3d", "the k3d cluster --name, used when --management-cluster=k3d",
+	)
+}
+
+func (m *k3dManagementCluster) Up() error {
+	args := []string{"cluster", "create", m.clusterName}
+	println("Up(): creating k3d management cluster...\n")
+	return process.ExecJUnit("k3d", args, os.Environ())
+}
+
+func (m *k3dManagementCluster) Down() error {
+	args := []string{"cluster", "delete", m.clusterName}
+	println("Down(): deleting k3d management cluster...\n")
+	return process.ExecJUnit("k3d", args, os.Environ())
+}
+
+func (m *k3dManagementCluster) Kubeconfig() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+func (m *k3dManagementCluster) IsUp() (bool, error) {
+	lines, err := exec.CombinedOutputLines(
+		exec.Command("k3d", "cluster", "list", m.clusterName, "--no-headers"),
+	)
+	if err != nil {
+		return false, err
+	}
+	return len(lines) > 0, nil
+}
+
+func (m *k3dManagementCluster) DumpClusterLogs() error {
+	// TODO(kubetest2): k3d has no equivalent of `kind export logs`; wire
+	// up `docker logs` against the node containers once needed.
+	return nil
+}
+
+// minikubeManagementCluster runs Cluster API against a minikube-managed
+// bootstrap cluster.
+type minikubeManagementCluster struct {
+	profile string
+}
+
+func bindMinikubeFlags(d *minikubeManagementCluster, flags *pflag.FlagSet) {
+	flags.StringVar(
+		&d.profile, "minikube-profile", "capi-minikube", "the minikube -p profile name, used when --management-cluster=minikube",
+	)
+}
+
+func (m *minikubeManagementCluster) Up() error {
+	args := []string{"start", "-p", m.profile}
+	println("Up(): starting minikube management cluster...\n")
+	return process.ExecJUnit("minikube", args, os.Environ())
+}
+
+func (m *minikubeManagementCluster) Down() error {
+	args := []string{"delete", "-p", m.profile}
+	println("Down(): deleting minikube management cluster...\n")
+	return process.ExecJUnit("minikube", args, os.Environ())
+}
+
+func (m *minikubeManagementCluster) Kubeconfig() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+func (m *minikubeManagementCluster) IsUp() (bool, error) {
+	// `minikube status` exits non-zero when the profile isn't running,
+	// which isn't a real error here, just a "not up" answer.
+	err := exec.Command("minikube", "status", "-p", m.profile).Run()
+	return err == nil, nil
+}
+
+func (m *minikubeManagementCluster) DumpClusterLogs() error {
+	args := []string{"logs", "-p", m.profile}
+	println("DumpClusterLogs(): exporting minikube logs...\n")
+	return process.ExecJUnit("minikube", args, os.Environ())
+}
+
+// externalManagementCluster skips cluster creation entirely, using an
+// already-running cluster reached via --management-kubeconfig (or the
+// default kubeconfig) as the CAPI bootstrap/management cluster.
+type externalManagementCluster struct {
+	kubeconfigPath string
+}
+
+func bindExternalFlags(d *externalManagementCluster, flags *pflag.FlagSet) {
+	flags.StringVar(
+		&d.kubeconfigPath, "management-kubeconfig", "", "kubeconfig of the existing cluster to use as the CAPI management cluster, used when --management-cluster=external",
+	)
+}
+
+func (m *externalManagementCluster) Up() error {
+	println("Up(): --management-cluster=external, skipping management cluster creation\n")
+	return nil
+}
+
+func (m *externalManagementCluster) Down() error {
+	println("Down(): --management-cluster=external, leaving the existing cluster running\n")
+	return nil
+}
+
+func (m *externalManagementCluster) Kubeconfig() (string, error) {
+	if m.kubeconfigPath != "" {
+		return m.kubeconfigPath, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+func (m *externalManagementCluster) IsUp() (bool, error) {
+	kubeconfig, err := m.Kubeconfig()
+	if err != nil {
+		return false, err
+	}
+	lines, err := exec.CombinedOutputLines(
+		exec.Command("kubectl", "--kubeconfig", kubeconfig, "get", "nodes", "-o=name"),
+	)
+	if err != nil {
+		return false, err
+	}
+	return len(lines) > 0, nil
+}
+
+func (m *externalManagementCluster) DumpClusterLogs() error {
+	// nothing to dump: kubetest2 did not create this cluster
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// clusterNameLabel selects the CAPI objects owned by a given workload
+// cluster.
+const clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+// Upgrade patches the workload cluster's KubeadmControlPlane and
+// MachineDeployments to toVersion (and, if --upgrade-image is set, the
+// infrastructure machine template's image reference) and waits for the
+// rollout to complete. env is the management cluster's environment (see
+// managementClusterEnv), since all of these objects live there rather
+// than on the workload cluster.
+func (d *deployer) Upgrade(toVersion string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	kcp, err := d.kubeadmControlPlaneName(ctx, env)
+	if err != nil {
+		return err
+	}
+
+	println("Upgrade(): patching KubeadmControlPlane version...\n")
+	patch := fmt.Sprintf(`{"spec":{"version":%q}}`, toVersion)
+	args := []string{"patch", "kubeadmcontrolplane", kcp, "--type=merge", "--patch", patch}
+	if err := process.ExecJUnitContext(ctx, "kubectl", args, env); err != nil {
+		return err
+	}
+
+	mds, err := d.machineDeploymentNames(ctx, env)
+	if err != nil {
+		return err
+	}
+	for _, md := range mds {
+		println(fmt.Sprintf("Upgrade(): patching MachineDeployment %s version...\n", md))
+		patch := fmt.Sprintf(`{"spec":{"template":{"spec":{"version":%q}}}}`, toVersion)
+		args := []string{"patch", "machinedeployment", md, "--type=merge", "--patch", patch}
+		if err := process.ExecJUnitContext(ctx, "kubectl", args, env); err != nil {
+			return err
+		}
+	}
+
+	if d.upgradeImage != "" {
+		println("Upgrade(): patching infrastructure machine template image...\n")
+		if err := d.patchInfraMachineTemplateImage(ctx, d.upgradeImage, env); err != nil {
+			return err
+		}
+	}
+
+	println("Upgrade(): waiting for KubeadmControlPlane rollout...\n")
+	args = []string{"wait", "--for=condition=Ready", "kubeadmcontrolplane/" + kcp, "--timeout=-1m"}
+	if err := process.ExecJUnitContext(ctx, "kubectl", args, env); err != nil {
+		return err
+	}
+
+	println("Upgrade(): waiting for all Machines to become Ready...\n")
+	args = []string{"wait", "--for=condition=MachinesReady", "cluster/" + d.workloadClusterName, "--timeout=-1m"}
+	return process.ExecJUnitContext(ctx, "kubectl", args, env)
+}
+
+func (d *deployer) kubeadmControlPlaneName(ctx context.Context, env []string) (string, error) {
+	args := []string{"get", "kubeadmcontrolplane", "-l", clusterNameLabel + "=" + d.workloadClusterName, "-o=jsonpath={.items[0].metadata.name}"}
+	kubectl := exec.CommandContext(ctx, "kubectl", args...)
+	kubectl.Env = env
+	out, err := kubectl.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find KubeadmControlPlane for cluster %q: %w", d.workloadClusterName, err)
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("no KubeadmControlPlane found for cluster %q", d.workloadClusterName)
+	}
+	return string(out), nil
+}
+
+func (d *deployer) machineDeploymentNames(ctx context.Context, env []string) ([]string, error) {
+	args := []string{"get", "machinedeployment", "-l", clusterNameLabel + "=" + d.workloadClusterName, "-o=jsonpath={.items[*].metadata.name}"}
+	kubectl := exec.CommandContext(ctx, "kubectl", args...)
+	kubectl.Env = env
+	out, err := kubectl.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MachineDeployments for cluster %q: %w", d.workloadClusterName, err)
+	}
+	return splitFields(string(out)), nil
+}
+
+// patchInfraMachineTemplateImage patches the image reference of the
+// provider-specific infrastructure machine template backing the
+// workload cluster's control-plane and worker machines. There is no
+// generic "machinetemplates" kind in Cluster API: every infrastructure
+// provider registers its own (dockermachinetemplates,
+// awsmachinetemplates, ...), so the resource kind to query is either
+// given explicitly via --infrastructure-machine-template-kind or
+// derived from the provider name. This patches the conventional
+// "spec.template.spec.imageRef.name" shape used by the kind/docker and
+// cluster-api-provider-* templates.
+func (d *deployer) patchInfraMachineTemplateImage(ctx context.Context, image string, env []string) error {
+	kind, err := d.infraMachineTemplateResourceKind()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"get", kind, "-l", clusterNameLabel + "=" + d.workloadClusterName, "-o=jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}"}
+	kubectl := exec.CommandContext(ctx, "kubectl", args...)
+	kubectl.Env = env
+	out, err := kubectl.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list %s for cluster %q: %w", kind, d.workloadClusterName, err)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"imageRef": map[string]interface{}{"name": image},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range splitFields(string(out)) {
+		args := []string{"patch", kind, name, "--type=merge", "--patch", string(patch)}
+		if err := process.ExecJUnitContext(ctx, "kubectl", args, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// infraMachineTemplateResourceKind resolves the provider-specific
+// machine-template resource kind to patch for --upgrade-image: either
+// the explicit --infrastructure-machine-template-kind override, or the
+// conventional "<provider>machinetemplates" plural derived from
+// --provider / the E2EConfig infrastructure provider name.
+func (d *deployer) infraMachineTemplateResourceKind() (string, error) {
+	if d.infraMachineTemplateKind != "" {
+		return d.infraMachineTemplateKind, nil
+	}
+	provider := d.provider
+	if d.e2eConfig != nil {
+		provider = d.e2eConfig.Infrastructure.Name
+	}
+	if provider == "" {
+		return "", fmt.Errorf("--upgrade-image requires --infrastructure-machine-template-kind when --provider is not set")
+	}
+	return provider + "machinetemplates", nil
+}
+
+func splitFields(s string) []string {
+	return strings.Fields(s)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kindConfig is a minimal representation of the kind.x-k8s.io/v1alpha4
+// Cluster config, just enough of it to synthesize (or merge onto) a
+// multi-node topology from flags.
+type kindConfig struct {
+	Kind         string                `yaml:"kind"`
+	APIVersion   string                `yaml:"apiVersion"`
+	Nodes        []kindConfigNode      `yaml:"nodes,omitempty"`
+	Networking   *kindConfigNetworking `yaml:"networking,omitempty"`
+	FeatureGates map[string]bool       `yaml:"featureGates,omitempty"`
+}
+
+type kindConfigNode struct {
+	Role              string                  `yaml:"role"`
+	ExtraPortMappings []kindConfigPortMapping `yaml:"extraPortMappings,omitempty"`
+}
+
+type kindConfigPortMapping struct {
+	ContainerPort int32  `yaml:"containerPort"`
+	HostPort      int32  `yaml:"hostPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+type kindConfigNetworking struct {
+	APIServerAddress string `yaml:"apiServerAddress,omitempty"`
+}
+
+// kindConfigOverrides carries the multi-node config-builder flag values
+// (and whether each node-count flag was explicitly set, so merging onto
+// an existing --config doesn't clobber its topology with defaults).
+type kindConfigOverrides struct {
+	ControlPlaneNodes    int
+	ControlPlaneNodesSet bool
+	WorkerNodes          int
+	WorkerNodesSet       bool
+	FeatureGates         string
+	ExtraPortMappings    string
+	APIServerAddress     string
+}
+
+// wantsSynthesizedConfig reports whether any multi-node config-builder
+// flag was set to something other than its single-node default, meaning
+// a --config should be synthesized even though none was passed.
+func (d *Deployer) wantsSynthesizedConfig() bool {
+	return d.controlPlaneNodes != 1 || d.workerNodes != 0 ||
+		d.featureGates != "" || d.extraPortMappings != "" || d.apiServerAddress != ""
+}
+
+// buildKindConfig synthesizes (when configPath == "") or loads-and-merges
+// (when configPath != "") a kind Cluster config reflecting the
+// --control-plane-nodes/--worker-nodes/--kubernetes-feature-gates/
+// --extra-port-mappings/--api-server-address flags, and writes it to a
+// tempfile whose path is returned for use with --config.
+func buildKindConfig(configPath string, o kindConfigOverrides) (string, error) {
+	var cfg *kindConfig
+	if configPath != "" {
+		raw, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read --config %q: %w", configPath, err)
+		}
+		cfg = &kindConfig{}
+		if err := yaml.Unmarshal(raw, cfg); err != nil {
+			return "", fmt.Errorf("failed to parse --config %q: %w", configPath, err)
+		}
+		if o.ControlPlaneNodesSet {
+			cfg.Nodes = reconcileNodeCount(cfg.Nodes, "control-plane", o.ControlPlaneNodes)
+		}
+		if o.WorkerNodesSet {
+			cfg.Nodes = reconcileNodeCount(cfg.Nodes, "worker", o.WorkerNodes)
+		}
+	} else {
+		cfg = &kindConfig{
+			Kind:       "Cluster",
+			APIVersion: "kind.x-k8s.io/v1alpha4",
+		}
+		for i := 0; i < o.ControlPlaneNodes; i++ {
+			cfg.Nodes = append(cfg.Nodes, kindConfigNode{Role: "control-plane"})
+		}
+		for i := 0; i < o.WorkerNodes; i++ {
+			cfg.Nodes = append(cfg.Nodes, kindConfigNode{Role: "worker"})
+		}
+	}
+
+	if o.FeatureGates != "" {
+		gates, err := parseFeatureGates(o.FeatureGates)
+		if err != nil {
+			return "", err
+		}
+		if cfg.FeatureGates == nil {
+			cfg.FeatureGates = map[string]bool{}
+		}
+		for k, v := range gates {
+			cfg.FeatureGates[k] = v
+		}
+	}
+
+	if o.APIServerAddress != "" {
+		cfg.Networking = &kindConfigNetworking{APIServerAddress: o.APIServerAddress}
+	}
+
+	if o.ExtraPortMappings != "" {
+		mappings, err := parsePortMappings(o.ExtraPortMappings)
+		if err != nil {
+			return "", err
+		}
+		idx := -1
+		for i, n := range cfg.Nodes {
+			if n.Role == "control-plane" {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			cfg.Nodes = append(cfg.Nodes, kindConfigNode{Role: "control-plane"})
+			idx = len(cfg.Nodes) - 1
+		}
+		cfg.Nodes[idx].ExtraPortMappings = append(cfg.Nodes[idx].ExtraPortMappings, mappings...)
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	tmpfile, err := ioutil.TempFile("", "kubetest2-kind-config-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer tmpfile.Close()
+	if _, err := tmpfile.Write(out); err != nil {
+		return "", err
+	}
+	return tmpfile.Name(), nil
+}
+
+// reconcileNodeCount adds or removes trailing nodes of the given role so
+// that nodes contains exactly want of them, preserving all nodes of
+// other roles and any already-configured fields on the ones that remain.
+func reconcileNodeCount(nodes []kindConfigNode, role string, want int) []kindConfigNode {
+	var have int
+	for _, n := range nodes {
+		if n.Role == role {
+			have++
+		}
+	}
+	for ; have < want; have++ {
+		nodes = append(nodes, kindConfigNode{Role: role})
+	}
+	for ; have > want; have-- {
+		for i := len(nodes) - 1; i >= 0; i-- {
+			if nodes[i].Role == role {
+				nodes = append(nodes[:i], nodes[i+1:]...)
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// parseFeatureGates parses a comma-separated "key=value,key=value" list
+// into the map form kind expects.
+func parseFeatureGates(s string) (map[string]bool, error) {
+	gates := map[string]bool{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid --kubernetes-feature-gates entry %q, want key=value", pair)
+		}
+		v, err := strconv.ParseBool(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --kubernetes-feature-gates entry %q: %w", pair, err)
+		}
+		gates[kv[0]] = v
+	}
+	return gates, nil
+}
+
+// parsePortMappings parses a comma-separated list of
+// "containerPort:hostPort[/protocol]" entries.
+func parsePortMappings(s string) ([]kindConfigPortMapping, error) {
+	var mappings []kindConfigPortMapping
+	for _, entry := range strings.Split(s, ",") {
+		if entry == "" {
+			continue
+		}
+		protocol := ""
+		if idx := strings.Index(entry, "/"); idx != -1 {
+			protocol = entry[idx+1:]
+			entry = entry[:idx]
+		}
+		ports := strings.SplitN(entry, ":", 2)
+		if len(ports) != 2 {
+			return nil, fmt.Errorf("invalid --extra-port-mappings entry %q, want containerPort:hostPort[/protocol]", entry)
+		}
+		containerPort, err := strconv.ParseInt(ports[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --extra-port-mappings entry %q: %w", entry, err)
+		}
+		hostPort, err := strconv.ParseInt(ports[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --extra-port-mappings entry %q: %w", entry, err)
+		}
+		mappings = append(mappings, kindConfigPortMapping{
+			ContainerPort: int32(containerPort),
+			HostPort:      int32(hostPort),
+			Protocol:      protocol,
+		})
+	}
+	return mappings, nil
+}
@@ -18,10 +18,13 @@ limitations under the License.
 package deployer
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -42,7 +45,8 @@ func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
 		logsDir:       filepath.Join(opts.ArtifactsDir(), "logs"),
 	}
 	// register flags and return
-	return d, bindFlags(d)
+	d.flags = bindFlags(d)
+	return d, d.flags
 }
 
 // assert that New implements types.NewDeployer
@@ -62,6 +66,16 @@ type Deployer struct {
 	kubeconfigPath string // --kubeconfig flag for kind create cluster
 	kubeRoot       string // --kube-root for kind build node-image
 	verbosity      int    // --verbosity for kind
+	wait           string // --wait flag for kind create cluster
+	upTimeout      string // --timeout for the post-create node-readiness wait
+	// multi-node config-builder flags, used to synthesize (or merge onto)
+	// --config
+	flags             *pflag.FlagSet
+	controlPlaneNodes int
+	workerNodes       int
+	featureGates      string
+	extraPortMappings string
+	apiServerAddress  string
 }
 
 func (d *Deployer) Kubeconfig() (string, error) {
@@ -102,6 +116,27 @@ func bindFlags(d *Deployer) *pflag.FlagSet {
 	flags.IntVar(
 		&d.verbosity, "verbosity", 0, "--verbosity flag for kind",
 	)
+	flags.StringVar(
+		&d.wait, "wait", "", "--wait duration forwarded to kind create cluster, e.g. 60s",
+	)
+	flags.StringVar(
+		&d.upTimeout, "up-timeout", "5m", "maximum time to wait for all nodes to report Ready after kind create cluster completes",
+	)
+	flags.IntVar(
+		&d.controlPlaneNodes, "control-plane-nodes", 1, "number of control-plane nodes to synthesize into --config when --config is not set; if given explicitly with --config, the config's control-plane node count is adjusted to match",
+	)
+	flags.IntVar(
+		&d.workerNodes, "worker-nodes", 0, "number of worker nodes to synthesize into --config when --config is not set; if given explicitly with --config, the config's worker node count is adjusted to match",
+	)
+	flags.StringVar(
+		&d.featureGates, "kubernetes-feature-gates", "", "comma-separated key=value feature gates merged into the kind config",
+	)
+	flags.StringVar(
+		&d.extraPortMappings, "extra-port-mappings", "", "comma-separated containerPort:hostPort[/protocol] mappings merged onto the first control-plane node",
+	)
+	flags.StringVar(
+		&d.apiServerAddress, "api-server-address", "", "the address kind's control-plane API server binds to, merged into the kind config's networking.apiServerAddress",
+	)
 	return flags
 }
 
@@ -127,8 +162,24 @@ func (d *Deployer) Up() error {
 		// we use the same logic / constant for Build()
 		args = append(args, "--image", kindDefaultBuiltImageName)
 	}
-	if d.configPath != "" {
-		args = append(args, "--config", d.configPath)
+	configPath := d.configPath
+	if configPath != "" || d.wantsSynthesizedConfig() {
+		var err error
+		configPath, err = buildKindConfig(d.configPath, kindConfigOverrides{
+			ControlPlaneNodes:    d.controlPlaneNodes,
+			ControlPlaneNodesSet: d.flags.Changed("control-plane-nodes"),
+			WorkerNodes:          d.workerNodes,
+			WorkerNodesSet:       d.flags.Changed("worker-nodes"),
+			FeatureGates:         d.featureGates,
+			ExtraPortMappings:    d.extraPortMappings,
+			APIServerAddress:     d.apiServerAddress,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if configPath != "" {
+		args = append(args, "--config", configPath)
 	}
 	if d.kubeconfigPath != "" {
 		args = append(args, "--kubeconfig", d.kubeconfigPath)
@@ -136,10 +187,26 @@ func (d *Deployer) Up() error {
 	if d.verbosity > 0 {
 		args = append(args, "--verbosity", strconv.Itoa(d.verbosity))
 	}
+	if d.wait != "" {
+		args = append(args, "--wait", d.wait)
+	}
 
 	println("Up(): creating kind cluster...\n")
 	// we want to see the output so use process.ExecJUnit
-	return process.ExecJUnit("kind", args, os.Environ())
+	if err := process.ExecJUnit("kind", args, os.Environ()); err != nil {
+		return err
+	}
+
+	upTimeout, err := time.ParseDuration(d.upTimeout)
+	if err != nil {
+		return err
+	}
+	println("Up(): waiting for nodes to become Ready...\n")
+	waitArgs := []string{"wait", "--for=condition=Ready", "nodes", "--all", "--timeout", upTimeout.String()}
+	if d.kubeconfigPath != "" {
+		waitArgs = append(waitArgs, "--kubeconfig", d.kubeconfigPath)
+	}
+	return process.ExecJUnit("kubectl", waitArgs, os.Environ())
 }
 
 func (d *Deployer) Down() error {
@@ -157,14 +224,42 @@ func (d *Deployer) Down() error {
 }
 
 func (d *Deployer) IsUp() (up bool, err error) {
-	// naively assume that if the api server reports nodes, the cluster is up
 	lines, err := exec.CombinedOutputLines(
-		exec.Command("kubectl", "get", "nodes", "-o=name"),
+		exec.Command("kubectl", "get", "nodes", "-o=json"),
 	)
 	if err != nil {
 		return false, metadata.NewJUnitError(err, strings.Join(lines, "\n"))
 	}
-	return len(lines) > 0, nil
+
+	var nodeList struct {
+		Items []struct {
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &nodeList); err != nil {
+		return false, fmt.Errorf("failed to parse kubectl get nodes output: %w", err)
+	}
+	if len(nodeList.Items) == 0 {
+		return false, nil
+	}
+	for _, node := range nodeList.Items {
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == "Ready" && cond.Status == "True" {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 func (d *Deployer) DumpClusterLogs() error {
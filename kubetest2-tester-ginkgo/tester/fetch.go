@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tester
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const tarballName = "kubernetes-test-linux-amd64.tar.gz"
+
+// fetchTestBinaries downloads (or reuses a cached copy of) the
+// kubernetes-test-linux-amd64 tarball for version, extracts e2e.test and
+// ginkgo into cacheDir, and returns their paths. version may be a
+// released tag (e.g. "v1.22.0") or a CI build reference (e.g.
+// "ci/latest" or "ci/v1.23.0-alpha.1.100+abcdef"), mirroring the
+// ci-artifacts pattern used by CAPI conformance jobs.
+func fetchTestBinaries(version, cacheDir string) (e2eTest, ginkgo string, err error) {
+	e2eTest = filepath.Join(cacheDir, "e2e.test")
+	ginkgo = filepath.Join(cacheDir, "ginkgo")
+	if fileExists(e2eTest) && fileExists(ginkgo) {
+		return e2eTest, ginkgo, nil
+	}
+
+	url, err := tarballURL(version)
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+
+	if err := extractBinaries(resp.Body, cacheDir, "e2e.test", "ginkgo"); err != nil {
+		return "", "", err
+	}
+
+	return e2eTest, ginkgo, nil
+}
+
+// tarballURL resolves version to a download URL for
+// kubernetes-test-linux-amd64.tar.gz, following either the release
+// dl.k8s.io layout or the gs://kubernetes-release-dev/ci CI layout when
+// version is prefixed with "ci/".
+func tarballURL(version string) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("--kubernetes-version is required")
+	}
+	if ci := strings.TrimPrefix(version, "ci/"); ci != version {
+		build, err := resolveCIBuild(ci)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("https://storage.googleapis.com/kubernetes-release-dev/ci/%s/%s", build, tarballName), nil
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return fmt.Sprintf("https://dl.k8s.io/%s/%s", version, tarballName), nil
+}
+
+// resolveCIBuild turns a CI version reference like "latest" into a
+// concrete build marker by reading its corresponding marker file from
+// the kubernetes-release-dev CI bucket.
+func resolveCIBuild(ref string) (string, error) {
+	if !strings.HasPrefix(ref, "latest") {
+		return ref, nil
+	}
+	url := fmt.Sprintf("https://storage.googleapis.com/kubernetes-release-dev/ci/%s.txt", ref)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve CI build marker %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve CI build marker %s: status %s", ref, resp.Status)
+	}
+	build, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(build)), nil
+}
+
+// extractBinaries streams a gzip'd tarball and writes the named files
+// (matched by base name, wherever they live in the archive) into dir.
+func extractBinaries(r io.Reader, dir string, names ...string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		base := filepath.Base(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !want[base] {
+			continue
+		}
+		out, err := os.OpenFile(filepath.Join(dir, base), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		out.Close()
+		delete(want, base)
+	}
+	if len(want) > 0 {
+		return fmt.Errorf("tarball did not contain expected binaries: %v", want)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tester implements the kubetest2 ginkgo tester, which runs the
+// Kubernetes e2e.test suite via ginkgo against a workload cluster.
+package tester
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// Name is the name of the tester
+const Name = "ginkgo"
+
+// New implements tester.New for ginkgo
+func New(opts types.Options) (types.Tester, *pflag.FlagSet) {
+	t := &Tester{
+		commonOptions: opts,
+	}
+	return t, bindFlags(t)
+}
+
+// assert that New implements types.NewTester
+var _ types.NewTester = New
+
+// Tester runs the upstream Kubernetes e2e.test suite via ginkgo against
+// the cluster under test, following the same ci-artifacts layout CAPI
+// providers use for conformance runs.
+type Tester struct {
+	commonOptions types.Options
+	// ginkgo specific details
+	kubeconfigPath    string
+	kubernetesVersion string
+	focus             string
+	skip              string
+	parallelNodes     int
+	conformance       bool
+	cacheDir          string
+}
+
+// helper used to create & bind a flagset to the tester
+func bindFlags(t *Tester) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(Name, pflag.ContinueOnError)
+	flags.StringVar(
+		&t.kubeconfigPath, "kubeconfig", "", "the kubeconfig of the workload cluster to test against",
+	)
+	flags.StringVar(
+		&t.kubernetesVersion, "kubernetes-version", "", "the Kubernetes version to fetch e2e.test and ginkgo for, e.g. v1.22.0 or ci/latest",
+	)
+	flags.StringVar(
+		&t.focus, "focus", "", "ginkgo --focus regexp",
+	)
+	flags.StringVar(
+		&t.skip, "skip", "", "ginkgo --skip regexp",
+	)
+	flags.IntVar(
+		&t.parallelNodes, "parallel-nodes", 1, "number of ginkgo parallel nodes to run tests across",
+	)
+	flags.BoolVar(
+		&t.conformance, "conformance", false, "run the [Conformance] focused test suite, overriding --focus",
+	)
+	flags.StringVar(
+		&t.cacheDir, "cache-dir", "", "directory to cache downloaded e2e.test/ginkgo binaries in, defaults to a temp dir",
+	)
+	return flags
+}
+
+// assert that Tester implements types.Tester
+var _ types.Tester = &Tester{}
+
+// Test downloads the e2e.test/ginkgo binaries matching --kubernetes-version
+// and runs them against the workload cluster, copying junit results and
+// e2e.log into ArtifactsDir().
+func (t *Tester) Test() error {
+	if t.conformance {
+		t.focus = `\[Conformance\]`
+	}
+
+	kubeconfig := t.kubeconfigPath
+	if kubeconfig == "" {
+		var err error
+		kubeconfig, err = defaultKubeconfig()
+		if err != nil {
+			return err
+		}
+	}
+
+	cacheDir := t.cacheDir
+	if cacheDir == "" {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "kubetest2-tester-ginkgo")
+		if err != nil {
+			return err
+		}
+	}
+
+	println("Test(): fetching e2e.test and ginkgo binaries...\n")
+	e2eTest, ginkgo, err := fetchTestBinaries(t.kubernetesVersion, cacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to fetch test binaries: %w", err)
+	}
+
+	artifactsDir := t.commonOptions.ArtifactsDir()
+	args := []string{
+		"--nodes", fmt.Sprintf("%d", t.parallelNodes),
+	}
+	if t.focus != "" {
+		args = append(args, "--focus", t.focus)
+	}
+	if t.skip != "" {
+		args = append(args, "--skip", t.skip)
+	}
+	args = append(args,
+		e2eTest,
+		"--",
+		"--kubeconfig", kubeconfig,
+		"--report-dir", artifactsDir,
+	)
+
+	logFile, err := os.Create(filepath.Join(artifactsDir, "e2e.log"))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	println("Test(): running e2e.test via ginkgo, logging to e2e.log...\n")
+	cmd := exec.Command(ginkgo, args...).
+		SetEnv(os.Environ()...).
+		SetStdout(io.MultiWriter(os.Stdout, logFile)).
+		SetStderr(io.MultiWriter(os.Stderr, logFile))
+	if err := cmd.Run(); err != nil {
+		// junit_*.xml from --report-dir land directly in artifactsDir;
+		// surface the e2e.log location so failures aggregate the way
+		// the rest of kubetest2 expects.
+		return metadata.NewJUnitError(err, fmt.Sprintf("e2e.test failed, see %s", logFile.Name()))
+	}
+
+	return nil
+}
+
+func defaultKubeconfig() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}